@@ -0,0 +1,66 @@
+package accesscontrol
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/authn"
+	"github.com/grafana/grafana/pkg/services/authn/scope"
+)
+
+func TestRequireScope(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	withIdentity := func(identity *authn.Identity) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/api/auth/keys", nil)
+		return r.WithContext(authn.WithIdentity(context.Background(), identity))
+	}
+
+	t.Run("no identity on the request passes through", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/auth/keys", nil)
+		rec := httptest.NewRecorder()
+
+		RequireScope("apikeys", "write")(ok).ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("unrestricted identity (nil scopes) passes through", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		RequireScope("apikeys", "write")(ok).ServeHTTP(rec, withIdentity(&authn.Identity{}))
+		require.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("identity holding the required scope passes through", func(t *testing.T) {
+		identity := &authn.Identity{Scopes: []scope.Scope{{Resource: "apikeys", Target: "write"}}}
+		rec := httptest.NewRecorder()
+
+		RequireScope("apikeys", "write")(ok).ServeHTTP(rec, withIdentity(identity))
+		require.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("identity missing the required scope is rejected", func(t *testing.T) {
+		identity := &authn.Identity{Scopes: []scope.Scope{{Resource: "dashboards", Target: "read"}}}
+		rec := httptest.NewRecorder()
+
+		RequireScope("apikeys", "write")(ok).ServeHTTP(rec, withIdentity(identity))
+		require.Equal(t, http.StatusForbidden, rec.Code)
+	})
+
+	t.Run("orgs verifier enforces an exact target match", func(t *testing.T) {
+		identity := &authn.Identity{Scopes: []scope.Scope{{Resource: "orgs", Target: "1"}}}
+
+		rec := httptest.NewRecorder()
+		RequireScope("orgs", "1")(ok).ServeHTTP(rec, withIdentity(identity))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		rec = httptest.NewRecorder()
+		RequireScope("orgs", "2")(ok).ServeHTTP(rec, withIdentity(identity))
+		require.Equal(t, http.StatusForbidden, rec.Code)
+	})
+}
@@ -0,0 +1,47 @@
+package apikey
+
+import "context"
+
+// MutationEvent is published whenever a key is revoked or deleted, so
+// callers that cache verification results (see authn/clients.APIKey) can
+// invalidate their cache instead of waiting out its TTL.
+type MutationEvent struct {
+	ID int64
+}
+
+// MutationHandler is called synchronously from within RevokeAPIKey/
+// DeleteAPIKey after the write commits.
+type MutationHandler func(ctx context.Context, e MutationEvent)
+
+// Service is the storage and lifecycle interface for API keys, backing the
+// authn.clients.APIKey client and the key management HTTP API.
+type Service interface {
+	GetAPIKeyByHash(ctx context.Context, hash string) (*APIKey, error)
+	GetApiKeyByName(ctx context.Context, query *GetByNameQuery) error
+
+	// CreateAPIKey mints a new key and returns the generated secret
+	// alongside the stored row; the secret itself is never persisted.
+	// cmd.Scopes must already have been validated against the creating
+	// identity's own scopes by the caller (see scope.Subset) — this is the
+	// delegation mode: minting can narrow permissions but never widen them.
+	CreateAPIKey(ctx context.Context, cmd *CreateAPIKeyCommand) (*APIKey, string, error)
+	RevokeAPIKey(ctx context.Context, orgID, keyID int64) error
+	DeleteAPIKey(ctx context.Context, orgID, keyID int64) error
+
+	// UpdateAPIKeySecurity updates the AllowedIPs and
+	// BoundClientCertFingerprint on an existing key, so an operator can
+	// tighten (or loosen) a key's network/cert restrictions without revoking
+	// and re-minting it. Like RevokeAPIKey/DeleteAPIKey it publishes a
+	// MutationEvent, so a cached positive result reflects the new
+	// restriction immediately rather than after the cache's TTL.
+	UpdateAPIKeySecurity(ctx context.Context, orgID, keyID int64, allowedIPs []string, certFingerprint string) error
+
+	// UpdateAPIKeyLastUsedDates flushes a batch of key ids coalesced by
+	// lastused.Recorder in a single statement.
+	UpdateAPIKeyLastUsedDates(ctx context.Context, keyIDs []int64) error
+
+	// OnMutation registers fn to be called whenever a key is revoked or
+	// deleted, so the in-memory verification cache can drop it immediately
+	// rather than serving a revoked key until its TTL expires.
+	OnMutation(fn MutationHandler)
+}
@@ -0,0 +1,133 @@
+package apikey
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/grafana/grafana/pkg/services/authn"
+	"github.com/grafana/grafana/pkg/services/authn/scope"
+	"github.com/grafana/grafana/pkg/services/org"
+)
+
+// updateKeySecurityRequest is the /api/auth/keys/{id} PATCH body.
+type updateKeySecurityRequest struct {
+	AllowedIPs                 []string `json:"allowedIPs"`
+	BoundClientCertFingerprint string   `json:"boundClientCertFingerprint"`
+}
+
+// createKeyRequest is the /api/auth/keys POST body.
+type createKeyRequest struct {
+	Name          string   `json:"name"`
+	Role          string   `json:"role"`
+	Scopes        []string `json:"scopes"`
+	AllowedIPs    []string `json:"allowedIPs"`
+	SecondsToLive *int64   `json:"secondsToLive"`
+}
+
+// Handler exposes the key-management HTTP surface (minting, in particular)
+// on top of a Service.
+type Handler struct {
+	service Service
+}
+
+func ProvideHandler(service Service) *Handler {
+	return &Handler{service: service}
+}
+
+// CreateAPIKey handles POST /api/auth/keys. The minted key's scopes are
+// validated against the calling identity's own scopes before the key is
+// created: this is the delegation mode, a caller can only ever mint a key
+// with a subset of what it can already do, never more. The minted key's
+// Role is checked the same way against the caller's own org role, since a
+// key's Role — not just its Scopes — determines what it can do.
+func (h *Handler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	identity, ok := authn.IdentityFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthenticated", http.StatusUnauthorized)
+		return
+	}
+
+	var req createKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	role := org.RoleType(req.Role)
+	if !role.IsValid() {
+		http.Error(w, "invalid role", http.StatusBadRequest)
+		return
+	}
+	if !identity.OrgRoles[identity.OrgID].Includes(role) {
+		http.Error(w, "requested role exceeds the caller's own role", http.StatusForbidden)
+		return
+	}
+
+	childScopes, err := scope.ParseScopes(req.Scopes)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !scope.Subset(childScopes, identity.Scopes) {
+		http.Error(w, "requested scopes exceed the caller's own scopes", http.StatusForbidden)
+		return
+	}
+
+	key, secret, err := h.service.CreateAPIKey(r.Context(), &CreateAPIKeyCommand{
+		OrgId:         identity.OrgID,
+		Name:          req.Name,
+		Role:          role,
+		Scopes:        req.Scopes,
+		AllowedIPs:    req.AllowedIPs,
+		SecondsToLive: req.SecondsToLive,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"id":   key.Id,
+		"name": key.Name,
+		"key":  secret,
+	})
+}
+
+// UpdateAPIKeySecurity handles PATCH /api/auth/keys/{id}, letting an admin
+// tighten (or loosen) a key's AllowedIPs/BoundClientCertFingerprint without
+// revoking and re-minting the key. Only an org admin of the caller's own
+// org may do this: the service call below scopes the update to that org,
+// but without this check any member of the org could loosen another
+// member's key.
+func (h *Handler) UpdateAPIKeySecurity(w http.ResponseWriter, r *http.Request, idParam string) {
+	identity, ok := authn.IdentityFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthenticated", http.StatusUnauthorized)
+		return
+	}
+
+	if !identity.OrgRoles[identity.OrgID].Includes(org.RoleAdmin) {
+		http.Error(w, "org admin role required", http.StatusForbidden)
+		return
+	}
+
+	keyID, err := strconv.ParseInt(idParam, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid key id", http.StatusBadRequest)
+		return
+	}
+
+	var req updateKeySecurityRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.UpdateAPIKeySecurity(r.Context(), identity.OrgID, keyID, req.AllowedIPs, req.BoundClientCertFingerprint); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
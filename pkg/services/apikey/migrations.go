@@ -0,0 +1,22 @@
+package apikey
+
+import (
+	"github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+)
+
+// AddMigrations registers the api_key schema changes this package depends
+// on. It is called from the main migration list the same way every other
+// service's AddMigrations is.
+func AddMigrations(mg *migrator.Migrator) {
+	mg.AddMigration("add scopes column to api_key", migrator.NewAddColumnMigration(migrator.Table{Name: "api_key"}, &migrator.Column{
+		Name: "scopes", Type: migrator.DB_Text, Nullable: true,
+	}))
+
+	mg.AddMigration("add allowed_ips column to api_key", migrator.NewAddColumnMigration(migrator.Table{Name: "api_key"}, &migrator.Column{
+		Name: "allowed_ips", Type: migrator.DB_Text, Nullable: true,
+	}))
+
+	mg.AddMigration("add bound_client_cert_fingerprint column to api_key", migrator.NewAddColumnMigration(migrator.Table{Name: "api_key"}, &migrator.Column{
+		Name: "bound_client_cert_fingerprint", Type: migrator.DB_NVarchar, Length: 64, Nullable: true,
+	}))
+}
@@ -0,0 +1,55 @@
+package apikey
+
+import (
+	"time"
+
+	"github.com/grafana/grafana/pkg/services/org"
+)
+
+// APIKey is a single org-wide or service-account API key.
+type APIKey struct {
+	Id               int64
+	OrgId            int64
+	Name             string
+	Key              string
+	Role             org.RoleType
+	Created          time.Time
+	Updated          time.Time
+	Expires          *int64
+	ServiceAccountId *int64
+	IsRevoked        *bool
+	LastUsedAt       *time.Time
+
+	// Scopes narrows the key's effective permissions below Role. A nil or
+	// empty Scopes means unrestricted, matching the behavior of keys minted
+	// before scopes existed.
+	Scopes []string
+
+	// AllowedIPs restricts which source addresses the key can authenticate
+	// from. A nil or empty AllowedIPs accepts any address.
+	AllowedIPs []string
+
+	// BoundClientCertFingerprint, when set, requires the TLS client
+	// certificate presented with the request to hash to this value.
+	BoundClientCertFingerprint string
+}
+
+// GetByNameQuery looks a key up by its legacy (unprefixed) name + org.
+type GetByNameQuery struct {
+	KeyName string
+	OrgId   int64
+	Result  *APIKey
+}
+
+// CreateAPIKeyCommand mints a new key. Scopes, if set, must be a subset of
+// the creating identity's own effective scopes (see scope.Subset) — this is
+// the delegation mode: a caller can only ever narrow access, never widen it.
+type CreateAPIKeyCommand struct {
+	OrgId                      int64
+	Name                       string
+	Role                       org.RoleType
+	Scopes                     []string
+	AllowedIPs                 []string
+	BoundClientCertFingerprint string
+	SecondsToLive              *int64
+}
@@ -0,0 +1,29 @@
+package apikey
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+)
+
+// RegisterRoutes mounts the key-management HTTP surface, gating both
+// endpoints behind accesscontrol.RequireScope so an identity restricted to,
+// say, "apikeys:read" can't call either even though each is separately
+// gated by a finer check inside its own handler. RequireScope checks the
+// coarse "can this identity touch apikeys at all" question; CreateAPIKey's
+// scope.Subset/role checks and UpdateAPIKeySecurity's org-admin check
+// handle the finer "is this specific request within what it's allowed to
+// do" one.
+func RegisterRoutes(mux *http.ServeMux, h *Handler) {
+	mux.Handle("/api/auth/keys", accesscontrol.RequireScope("apikeys", "write")(http.HandlerFunc(h.CreateAPIKey)))
+
+	mux.Handle("/api/auth/keys/", accesscontrol.RequireScope("apikeys", "write")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		idParam := strings.TrimPrefix(r.URL.Path, "/api/auth/keys/")
+		h.UpdateAPIKeySecurity(w, r, idParam)
+	})))
+}
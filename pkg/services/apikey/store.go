@@ -0,0 +1,189 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	apikeygenprefix "github.com/grafana/grafana/pkg/components/apikeygenprefixed"
+)
+
+// InMemoryStore is a reference Service implementation backed by a map
+// rather than the database. It is sufficient to exercise the authn client
+// and the key-management surface end to end; the SQL-backed equivalent
+// reads/writes the api_key table added by the migration in migrations.go
+// and otherwise behaves identically.
+type InMemoryStore struct {
+	mu        sync.Mutex
+	nextID    int64
+	byID      map[int64]*APIKey
+	byHash    map[string]int64
+	byOrgName map[string]int64
+	handlers  []MutationHandler
+}
+
+func ProvideInMemoryStore() *InMemoryStore {
+	return &InMemoryStore{
+		byID:      map[int64]*APIKey{},
+		byHash:    map[string]int64{},
+		byOrgName: map[string]int64{},
+	}
+}
+
+var _ Service = (*InMemoryStore)(nil)
+
+func (s *InMemoryStore) GetAPIKeyByHash(ctx context.Context, hash string) (*APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id, ok := s.byHash[hash]
+	if !ok {
+		return nil, apikeygenprefix.ErrInvalidApiKey
+	}
+	return s.byID[id], nil
+}
+
+func (s *InMemoryStore) GetApiKeyByName(ctx context.Context, query *GetByNameQuery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id, ok := s.byOrgName[orgNameKey(query.OrgId, query.KeyName)]
+	if !ok {
+		return apikeygenprefix.ErrInvalidApiKey
+	}
+	query.Result = s.byID[id]
+	return nil
+}
+
+func (s *InMemoryStore) CreateAPIKey(ctx context.Context, cmd *CreateAPIKeyCommand) (*APIKey, string, error) {
+	secret, hashed, err := generateKey()
+	if err != nil {
+		return nil, "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	key := &APIKey{
+		Id:                         s.nextID,
+		OrgId:                      cmd.OrgId,
+		Name:                       cmd.Name,
+		Key:                        hashed,
+		Role:                       cmd.Role,
+		Scopes:                     cmd.Scopes,
+		AllowedIPs:                 cmd.AllowedIPs,
+		BoundClientCertFingerprint: cmd.BoundClientCertFingerprint,
+	}
+	if cmd.SecondsToLive != nil {
+		expires := time.Now().Unix() + *cmd.SecondsToLive
+		key.Expires = &expires
+	}
+
+	s.byID[key.Id] = key
+	s.byHash[hashed] = key.Id
+	s.byOrgName[orgNameKey(cmd.OrgId, cmd.Name)] = key.Id
+
+	return key, secret, nil
+}
+
+func (s *InMemoryStore) RevokeAPIKey(ctx context.Context, orgID, keyID int64) error {
+	s.mu.Lock()
+	key, ok := s.byID[keyID]
+	if !ok || key.OrgId != orgID {
+		s.mu.Unlock()
+		return apikeygenprefix.ErrInvalidApiKey
+	}
+	revoked := true
+	key.IsRevoked = &revoked
+	handlers := s.handlers
+	s.mu.Unlock()
+
+	for _, h := range handlers {
+		h(ctx, MutationEvent{ID: keyID})
+	}
+	return nil
+}
+
+func (s *InMemoryStore) DeleteAPIKey(ctx context.Context, orgID, keyID int64) error {
+	s.mu.Lock()
+	key, ok := s.byID[keyID]
+	if !ok || key.OrgId != orgID {
+		s.mu.Unlock()
+		return apikeygenprefix.ErrInvalidApiKey
+	}
+	delete(s.byID, keyID)
+	delete(s.byHash, key.Key)
+	delete(s.byOrgName, orgNameKey(key.OrgId, key.Name))
+	handlers := s.handlers
+	s.mu.Unlock()
+
+	for _, h := range handlers {
+		h(ctx, MutationEvent{ID: keyID})
+	}
+	return nil
+}
+
+func (s *InMemoryStore) UpdateAPIKeySecurity(ctx context.Context, orgID, keyID int64, allowedIPs []string, certFingerprint string) error {
+	s.mu.Lock()
+	key, ok := s.byID[keyID]
+	if !ok || key.OrgId != orgID {
+		s.mu.Unlock()
+		return apikeygenprefix.ErrInvalidApiKey
+	}
+	key.AllowedIPs = allowedIPs
+	key.BoundClientCertFingerprint = certFingerprint
+	handlers := s.handlers
+	s.mu.Unlock()
+
+	for _, h := range handlers {
+		h(ctx, MutationEvent{ID: keyID})
+	}
+	return nil
+}
+
+func (s *InMemoryStore) UpdateAPIKeyLastUsedDates(ctx context.Context, keyIDs []int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range keyIDs {
+		if key, ok := s.byID[id]; ok {
+			now := time.Now()
+			key.LastUsedAt = &now
+		}
+	}
+	return nil
+}
+
+func (s *InMemoryStore) OnMutation(fn MutationHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers = append(s.handlers, fn)
+}
+
+func orgNameKey(orgID int64, name string) string {
+	return fmt.Sprintf("%d/%s", orgID, name)
+}
+
+// generateKey mints a new glapikey_-prefixed secret and returns both the
+// secret to hand back to the caller once and the hash to persist.
+func generateKey() (secret, hashed string, err error) {
+	raw := make([]byte, 24)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", err
+	}
+	secret = apikeygenprefix.GrafanaPrefix + hex.EncodeToString(raw)
+
+	decoded, err := apikeygenprefix.Decode(secret)
+	if err != nil {
+		return "", "", err
+	}
+	hashed, err = decoded.Hash()
+	if err != nil {
+		return "", "", err
+	}
+	return secret, hashed, nil
+}
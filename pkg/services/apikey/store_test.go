@@ -0,0 +1,41 @@
+package apikey
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInMemoryStore_UpdateAPIKeySecurity(t *testing.T) {
+	ctx := context.Background()
+	store := ProvideInMemoryStore()
+
+	key, _, err := store.CreateAPIKey(ctx, &CreateAPIKeyCommand{OrgId: 1, Name: "k1"})
+	require.NoError(t, err)
+
+	var mutated []MutationEvent
+	store.OnMutation(func(ctx context.Context, e MutationEvent) {
+		mutated = append(mutated, e)
+	})
+
+	err = store.UpdateAPIKeySecurity(ctx, 1, key.Id, []string{"203.0.113.0/24"}, "deadbeef")
+	require.NoError(t, err)
+	require.Equal(t, []MutationEvent{{ID: key.Id}}, mutated)
+
+	got, err := store.GetAPIKeyByHash(ctx, key.Key)
+	require.NoError(t, err)
+	require.Equal(t, []string{"203.0.113.0/24"}, got.AllowedIPs)
+	require.Equal(t, "deadbeef", got.BoundClientCertFingerprint)
+}
+
+func TestInMemoryStore_UpdateAPIKeySecurity_WrongOrg(t *testing.T) {
+	ctx := context.Background()
+	store := ProvideInMemoryStore()
+
+	key, _, err := store.CreateAPIKey(ctx, &CreateAPIKeyCommand{OrgId: 1, Name: "k1"})
+	require.NoError(t, err)
+
+	err = store.UpdateAPIKeySecurity(ctx, 2, key.Id, []string{"203.0.113.0/24"}, "")
+	require.Error(t, err)
+}
@@ -11,37 +11,101 @@ import (
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/services/apikey"
 	"github.com/grafana/grafana/pkg/services/authn"
+	"github.com/grafana/grafana/pkg/services/authn/lastused"
+	"github.com/grafana/grafana/pkg/services/authn/scope"
 	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/pat"
 	"github.com/grafana/grafana/pkg/services/user"
-	"github.com/grafana/grafana/pkg/util"
+	"github.com/grafana/grafana/pkg/setting"
 	"github.com/grafana/grafana/pkg/util/errutil"
 )
 
 var (
-	ErrAPIKeyInvalid          = errutil.NewBase(errutil.StatusUnauthorized, "api-key.invalid", errutil.WithPublicMessage("Invalid API key"))
-	ErrAPIKeyExpired          = errutil.NewBase(errutil.StatusUnauthorized, "api-key.expired", errutil.WithPublicMessage("Expired API key"))
-	ErrAPIKeyRevoked          = errutil.NewBase(errutil.StatusUnauthorized, "api-key.revoked", errutil.WithPublicMessage("Revoked API key"))
-	ErrServiceAccountDisabled = errutil.NewBase(errutil.StatusUnauthorized, "service-account.disabled", errutil.WithPublicMessage("Disabled service account"))
+	ErrAPIKeyInvalid           = errutil.NewBase(errutil.StatusUnauthorized, "api-key.invalid", errutil.WithPublicMessage("Invalid API key"))
+	ErrAPIKeyExpired           = errutil.NewBase(errutil.StatusUnauthorized, "api-key.expired", errutil.WithPublicMessage("Expired API key"))
+	ErrAPIKeyRevoked           = errutil.NewBase(errutil.StatusUnauthorized, "api-key.revoked", errutil.WithPublicMessage("Revoked API key"))
+	ErrServiceAccountDisabled  = errutil.NewBase(errutil.StatusUnauthorized, "service-account.disabled", errutil.WithPublicMessage("Disabled service account"))
+	ErrAPIKeyTransportDisabled = errutil.NewBase(errutil.StatusUnauthorized, "api-key.transport-disabled", errutil.WithPublicMessage("API key transport is disabled"))
+	ErrAPIKeyIPNotAllowed      = errutil.NewBase(errutil.StatusUnauthorized, "api-key.ip-not-allowed", errutil.WithPublicMessage("API key is not allowed from this address"))
+	ErrAPIKeyCertMismatch      = errutil.NewBase(errutil.StatusUnauthorized, "api-key.cert-mismatch", errutil.WithPublicMessage("Client certificate does not match the API key"))
 )
 
 var _ authn.Client = new(APIKey)
 
-func ProvideAPIKey(apiKeyService apikey.Service, userService user.Service) *APIKey {
-	return &APIKey{
-		log:           log.New(authn.ClientAPIKey),
-		userService:   userService,
-		apiKeyService: apiKeyService,
+// lastUsedWindow is how long a key's last-used timestamp can lag behind
+// reality before it is flushed to storage.
+const lastUsedWindow = 60 * time.Second
+
+func ProvideAPIKey(cfg *setting.Cfg, apiKeyService apikey.Service, userService user.Service) *APIKey {
+	allowedTransports := cfg.APIKeyAllowedTransports
+	if len(allowedTransports) == 0 {
+		allowedTransports = []string{TransportBearer, TransportBasic}
+	}
+
+	s := &APIKey{
+		log:                log.New(authn.ClientAPIKey),
+		userService:        userService,
+		apiKeyService:      apiKeyService,
+		allowedTransports:  allowedTransports,
+		tokenExtractors:    buildTokenExtractors(allowedTransports, cfg.APIKeyHeaderName),
+		disabledExtractors: buildDisabledExtractors(allowedTransports, cfg.APIKeyHeaderName),
+		lastUsed:           lastused.NewRecorder("api_key", lastUsedWindow, apiKeyService.UpdateAPIKeyLastUsedDates),
+		trustedProxies:     cfg.TrustedProxies,
+		cache:              newTokenCache(cfg.APIKeyCacheSize, cfg.APIKeyCachePositiveTTL, cfg.APIKeyCacheNegativeTTL),
 	}
+
+	// Drop a key's cached verification result the moment it's revoked or
+	// deleted, rather than serving it out of the positive cache for up to
+	// its TTL after the fact.
+	apiKeyService.OnMutation(func(ctx context.Context, e apikey.MutationEvent) {
+		s.InvalidateCacheByID(e.ID)
+	})
+
+	return s
 }
 
 type APIKey struct {
-	log           log.Logger
-	userService   user.Service
-	apiKeyService apikey.Service
+	log                log.Logger
+	userService        user.Service
+	apiKeyService      apikey.Service
+	allowedTransports  []string
+	tokenExtractors    []authn.TokenExtractor
+	disabledExtractors []namedExtractor
+	lastUsed           *lastused.Recorder
+	trustedProxies     []string
+	cache              *tokenCache
+}
+
+// InvalidateCache drops any cached verification result for token.
+func (s *APIKey) InvalidateCache(token string) {
+	s.cache.invalidate(token)
+}
+
+// InvalidateCacheByID drops the cached verification result for the key with
+// the given ID, if any. It is subscribed to apiKeyService.OnMutation in
+// ProvideAPIKey so a revoked or deleted key stops authenticating out of the
+// cache immediately, instead of continuing to work until its positive TTL
+// expires.
+func (s *APIKey) InvalidateCacheByID(keyID int64) {
+	s.cache.invalidateByID(keyID)
+}
+
+// Run flushes batched last-used updates on the configured window. It is
+// registered alongside the other background services started at startup.
+func (s *APIKey) Run(ctx context.Context) error {
+	return s.lastUsed.Run(ctx)
 }
 
 func (s *APIKey) Authenticate(ctx context.Context, r *authn.Request) (*authn.Identity, error) {
-	apiKey, err := s.getAPIKey(ctx, getTokenFromRequest(r))
+	token, disabledTransport := s.getTokenFromRequest(r)
+	if token == "" {
+		if disabledTransport != "" {
+			return nil, ErrAPIKeyTransportDisabled.Errorf("API key was sent via the %q transport, which is disabled", disabledTransport)
+		}
+		return nil, ErrAPIKeyTransportDisabled.Errorf("no API key found on an allowed transport")
+	}
+
+	apiKey, err := s.getAPIKey(ctx, token, r)
 	if err != nil {
 		if errors.Is(err, apikeygen.ErrInvalidApiKey) {
 			return nil, ErrAPIKeyInvalid.Errorf("API key is invalid")
@@ -57,16 +121,27 @@ func (s *APIKey) Authenticate(ctx context.Context, r *authn.Request) (*authn.Ide
 		return nil, ErrAPIKeyRevoked.Errorf("Api key is revoked")
 	}
 
-	go func(id int64) {
-		defer func() {
-			if err := recover(); err != nil {
-				s.log.Error("api key authentication panic", "err", err)
+	if r.HTTPRequest != nil {
+		if len(apiKey.AllowedIPs) > 0 {
+			addr := clientIP(r.HTTPRequest, s.trustedProxies)
+			if addr == nil || !ipInCIDRs(addr, apiKey.AllowedIPs) {
+				return nil, ErrAPIKeyIPNotAllowed.Errorf("API key is not allowed from this address")
 			}
-		}()
-		if err := s.apiKeyService.UpdateAPIKeyLastUsedDate(context.Background(), id); err != nil {
-			s.log.Warn("failed to update last use date for api key", "id", id)
 		}
-	}(apiKey.Id)
+
+		if apiKey.BoundClientCertFingerprint != "" {
+			if !clientCertMatches(r.HTTPRequest, apiKey.BoundClientCertFingerprint) {
+				return nil, ErrAPIKeyCertMismatch.Errorf("client certificate does not match the API key")
+			}
+		}
+	}
+
+	s.lastUsed.Record(apiKey.Id)
+
+	scopes, err := scope.ParseScopes(apiKey.Scopes)
+	if err != nil {
+		return nil, ErrAPIKeyInvalid.Errorf("API key scopes are malformed: %w", err)
+	}
 
 	// if the api key don't belong to a service account construct the identity and return it
 	if apiKey.ServiceAccountId == nil || *apiKey.ServiceAccountId < 1 {
@@ -74,6 +149,7 @@ func (s *APIKey) Authenticate(ctx context.Context, r *authn.Request) (*authn.Ide
 			ID:       authn.NamespacedID(authn.NamespaceAPIKey, apiKey.Id),
 			OrgID:    apiKey.OrgId,
 			OrgRoles: map[int64]org.RoleType{apiKey.OrgId: apiKey.Role},
+			Scopes:   scopes,
 		}, nil
 	}
 
@@ -90,10 +166,32 @@ func (s *APIKey) Authenticate(ctx context.Context, r *authn.Request) (*authn.Ide
 		return nil, ErrServiceAccountDisabled.Errorf("Disabled service account")
 	}
 
-	return authn.IdentityFromSignedInUser(authn.NamespacedID(authn.NamespaceServiceAccount, usr.UserID), usr), nil
+	identity := authn.IdentityFromSignedInUser(authn.NamespacedID(authn.NamespaceServiceAccount, usr.UserID), usr)
+	identity.Scopes = scopes
+	return identity, nil
 }
 
-func (s *APIKey) getAPIKey(ctx context.Context, token string) (*apikey.APIKey, error) {
+// getAPIKey resolves token to its stored key, consulting the in-memory
+// cache first so that repeated requests with the same key skip both the DB
+// round-trip and the hash verification below. source identifies where the
+// request came from, for negative-cache backoff bucketing; it is best
+// effort and left empty when it can't be determined.
+func (s *APIKey) getAPIKey(ctx context.Context, token string, r *authn.Request) (*apikey.APIKey, error) {
+	source := ""
+	if r.HTTPRequest != nil {
+		if addr := clientIP(r.HTTPRequest, s.trustedProxies); addr != nil {
+			source = addr.String()
+		}
+	}
+
+	if cached, ok := s.cache.get(token); ok {
+		return cached, nil
+	}
+
+	if s.cache.isBlocked(source) {
+		return nil, apikeygen.ErrInvalidApiKey
+	}
+
 	fn := s.getFromToken
 	if !strings.HasPrefix(token, apikeygenprefix.GrafanaPrefix) {
 		fn = s.getFromTokenLegacy
@@ -101,9 +199,13 @@ func (s *APIKey) getAPIKey(ctx context.Context, token string) (*apikey.APIKey, e
 
 	apiKey, err := fn(ctx, token)
 	if err != nil {
+		if errors.Is(err, apikeygen.ErrInvalidApiKey) {
+			s.cache.recordInvalid(source)
+		}
 		return nil, err
 	}
 
+	s.cache.set(token, apiKey)
 	return apiKey, nil
 }
 
@@ -149,30 +251,42 @@ func (s *APIKey) ClientParams() *authn.ClientParams {
 	return &authn.ClientParams{}
 }
 
+// Test reports whether this client should attempt to authenticate r. A
+// token found on a disabled transport counts as a match too, even though
+// getTokenFromRequest won't hand it back: Authenticate is only ever called
+// after Test returns true, so a token that arrived on a disabled transport
+// has to make it there for ErrAPIKeyTransportDisabled to ever fire instead
+// of silently falling through to the next authn client.
 func (s *APIKey) Test(ctx context.Context, r *authn.Request) bool {
-	return looksLikeApiKey(getTokenFromRequest(r))
-}
-
-func looksLikeApiKey(token string) bool {
-	return token != ""
+	token, disabledTransport := s.getTokenFromRequest(r)
+	return token != "" || disabledTransport != ""
 }
 
-func getTokenFromRequest(r *authn.Request) string {
-	// api keys are only supported through http requests
-	if r.HTTPRequest == nil {
-		return ""
+// getTokenFromRequest runs the configured transport extractors in order and
+// returns the first token found. If none of the allowed transports carry a
+// token, it also checks the disabled transports, purely to report which one
+// the caller used: the token found there is never returned or accepted, so
+// an operator seeing ErrAPIKeyTransportDisabled can tell "nothing was sent"
+// apart from "something was sent on a transport I turned off" instead of
+// getting the same opaque rejection for both.
+//
+// A pat.Prefix-shaped token is skipped on every transport, allowed or
+// disabled: API keys and PATs share the same bearer/basic/header/query
+// transports, so without this check a glpat_-prefixed token sent as a
+// Bearer token would match here first and be rejected by getFromTokenLegacy
+// instead of ever reaching clients.PAT.
+func (s *APIKey) getTokenFromRequest(r *authn.Request) (token string, disabledTransport string) {
+	for _, extract := range s.tokenExtractors {
+		if token := extract(r); token != "" && !strings.HasPrefix(token, pat.Prefix) {
+			return token, ""
+		}
 	}
 
-	header := r.HTTPRequest.Header.Get("Authorization")
-
-	if strings.HasPrefix(header, bearerPrefix) {
-		return strings.TrimPrefix(header, bearerPrefix)
-	}
-	if strings.HasPrefix(header, basicPrefix) {
-		username, password, err := util.DecodeBasicAuthHeader(header)
-		if err == nil && username == "api_key" {
-			return password
+	for _, d := range s.disabledExtractors {
+		if token := d.extract(r); token != "" && !strings.HasPrefix(token, pat.Prefix) {
+			return "", d.transport
 		}
 	}
-	return ""
+
+	return "", ""
 }
@@ -0,0 +1,121 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/authn"
+	"github.com/grafana/grafana/pkg/services/authn/lastused"
+	"github.com/grafana/grafana/pkg/services/authn/scope"
+	"github.com/grafana/grafana/pkg/services/pat"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/util/errutil"
+)
+
+var (
+	ErrPATInvalid      = errutil.NewBase(errutil.StatusUnauthorized, "pat.invalid", errutil.WithPublicMessage("Invalid access token"))
+	ErrPATExpired      = errutil.NewBase(errutil.StatusUnauthorized, "pat.expired", errutil.WithPublicMessage("Expired access token"))
+	ErrPATIPNotAllowed = errutil.NewBase(errutil.StatusUnauthorized, "pat.ip-not-allowed", errutil.WithPublicMessage("Access token is not allowed from this address"))
+)
+
+var _ authn.Client = new(PAT)
+
+func ProvidePAT(cfg *setting.Cfg, patService pat.Service, userService user.Service) *PAT {
+	allowedTransports := cfg.APIKeyAllowedTransports
+	if len(allowedTransports) == 0 {
+		allowedTransports = []string{TransportBearer, TransportBasic}
+	}
+
+	return &PAT{
+		log:             log.New(authn.ClientPAT),
+		userService:     userService,
+		patService:      patService,
+		tokenExtractors: buildTokenExtractors(allowedTransports, cfg.APIKeyHeaderName),
+		lastUsed:        lastused.NewRecorder("pat", lastUsedWindow, patService.UpdateLastUsedDates),
+		trustedProxies:  cfg.TrustedProxies,
+	}
+}
+
+// PAT authenticates requests bearing a user-minted personal access token.
+// Unlike APIKey, the resulting identity is always the token owner's own
+// identity, narrowed by the token's scopes rather than a service account's.
+type PAT struct {
+	log             log.Logger
+	userService     user.Service
+	patService      pat.Service
+	tokenExtractors []authn.TokenExtractor
+	lastUsed        *lastused.Recorder
+	trustedProxies  []string
+}
+
+// Run flushes batched last-used updates on the configured window.
+func (s *PAT) Run(ctx context.Context) error {
+	return s.lastUsed.Run(ctx)
+}
+
+func (s *PAT) Authenticate(ctx context.Context, r *authn.Request) (*authn.Identity, error) {
+	token := s.getTokenFromRequest(r)
+
+	hash, err := pat.HashToken(token)
+	if err != nil {
+		return nil, ErrPATInvalid.Errorf("access token is invalid")
+	}
+
+	tok, err := s.patService.GetTokenByHash(ctx, hash)
+	if err != nil {
+		if errors.Is(err, pat.ErrInvalidToken) {
+			return nil, ErrPATInvalid.Errorf("access token is invalid")
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	if tok.Expired(now) {
+		return nil, ErrPATExpired.Errorf("access token has expired")
+	}
+
+	if r.HTTPRequest != nil {
+		if addr := clientIP(r.HTTPRequest, s.trustedProxies); addr != nil && !tok.IPAllowed(addr) {
+			return nil, ErrPATIPNotAllowed.Errorf("access token is not allowed from this address")
+		}
+	}
+
+	scopes, err := scope.ParseScopes(tok.Scopes)
+	if err != nil {
+		return nil, ErrPATInvalid.Errorf("access token scopes are malformed: %w", err)
+	}
+
+	usr, err := s.userService.GetSignedInUserWithCacheCtx(ctx, &user.GetSignedInUserQuery{
+		UserID: tok.UserID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.lastUsed.Record(tok.ID)
+
+	identity := authn.IdentityFromSignedInUser(authn.NamespacedID(authn.NamespaceUser, usr.UserID), usr)
+	identity.Scopes = scopes
+	return identity, nil
+}
+
+func (s *PAT) getTokenFromRequest(r *authn.Request) string {
+	for _, extract := range s.tokenExtractors {
+		if token := extract(r); token != "" {
+			return token
+		}
+	}
+	return ""
+}
+
+func (s *PAT) ClientParams() *authn.ClientParams {
+	return &authn.ClientParams{}
+}
+
+func (s *PAT) Test(ctx context.Context, r *authn.Request) bool {
+	token := s.getTokenFromRequest(r)
+	return len(token) > len(pat.Prefix) && token[:len(pat.Prefix)] == pat.Prefix
+}
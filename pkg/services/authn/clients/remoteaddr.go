@@ -0,0 +1,79 @@
+package clients
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// clientCertMatches reports whether the TLS peer certificate presented with
+// r hashes to fingerprint (hex-encoded SHA-256 of the DER-encoded cert). A
+// request with no peer certificate never matches a configured fingerprint.
+func clientCertMatches(r *http.Request, fingerprint string) bool {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return false
+	}
+	sum := sha256.Sum256(r.TLS.PeerCertificates[0].Raw)
+	return hex.EncodeToString(sum[:]) == fingerprint
+}
+
+// clientIP resolves the address a request actually originated from,
+// accounting for a reverse proxy chain. When RemoteAddr belongs to one of
+// trustedProxies, the rightmost X-Forwarded-For entry that is not itself a
+// trusted proxy is used instead; otherwise RemoteAddr is trusted as-is.
+// This mirrors the trust model operators already configure for Grafana's
+// other real-IP-sensitive checks (rate limiting, audit logging).
+func clientIP(r *http.Request, trustedProxies []string) net.IP {
+	addr := parseHostPort(r.RemoteAddr)
+	if addr == nil {
+		return nil
+	}
+
+	if !ipInCIDRs(addr, trustedProxies) {
+		return addr
+	}
+
+	forwarded := r.Header.Get("X-Forwarded-For")
+	if forwarded == "" {
+		return addr
+	}
+
+	parts := strings.Split(forwarded, ",")
+	for i := len(parts) - 1; i >= 0; i-- {
+		candidate := net.ParseIP(strings.TrimSpace(parts[i]))
+		if candidate == nil {
+			continue
+		}
+		if !ipInCIDRs(candidate, trustedProxies) {
+			return candidate
+		}
+	}
+
+	return addr
+}
+
+// ipInCIDRs reports whether addr falls within any of cidrs. A malformed
+// entry is skipped rather than erroring, since one bad entry in an operator
+// allowlist shouldn't break every other entry.
+func ipInCIDRs(addr net.IP, cidrs []string) bool {
+	for _, raw := range cidrs {
+		_, cidr, err := net.ParseCIDR(raw)
+		if err != nil {
+			continue
+		}
+		if cidr.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseHostPort(hostport string) net.IP {
+	host, _, err := net.SplitHostPort(hostport)
+	if err != nil {
+		host = hostport
+	}
+	return net.ParseIP(host)
+}
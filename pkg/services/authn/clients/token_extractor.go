@@ -0,0 +1,137 @@
+package clients
+
+import (
+	"strings"
+
+	"github.com/grafana/grafana/pkg/services/authn"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+// Transport names accepted in the [auth.api_key] allowed_transports setting.
+const (
+	TransportBearer = "bearer"
+	TransportBasic  = "basic"
+	TransportHeader = "header"
+	TransportQuery  = "query"
+)
+
+// allTransports enumerates every transport this client knows how to
+// extract a token from, allowed or not.
+var allTransports = []string{TransportBearer, TransportBasic, TransportHeader, TransportQuery}
+
+// defaultHeaderName is used when [auth.api_key] header_name is left empty.
+const defaultHeaderName = "X-Api-Key"
+
+// namedExtractor pairs an extractor with the transport name it implements,
+// so a disabled-transport check can report which transport a rejected
+// token arrived on.
+type namedExtractor struct {
+	transport string
+	extract   authn.TokenExtractor
+}
+
+func extractorFor(transport, headerName string) authn.TokenExtractor {
+	switch transport {
+	case TransportBearer:
+		return bearerTokenExtractor
+	case TransportBasic:
+		return basicTokenExtractor
+	case TransportHeader:
+		return headerTokenExtractor(headerName)
+	case TransportQuery:
+		return queryTokenExtractor
+	default:
+		return nil
+	}
+}
+
+// buildTokenExtractors turns the configured transport names into the
+// ordered chain of extractors an APIKey client tries against a request. The
+// order matches the configured transport order, so operators can make the
+// header/query fallbacks take precedence over Authorization if they want.
+func buildTokenExtractors(allowedTransports []string, headerName string) []authn.TokenExtractor {
+	if headerName == "" {
+		headerName = defaultHeaderName
+	}
+
+	extractors := make([]authn.TokenExtractor, 0, len(allowedTransports))
+	for _, transport := range allowedTransports {
+		if fn := extractorFor(strings.ToLower(strings.TrimSpace(transport)), headerName); fn != nil {
+			extractors = append(extractors, fn)
+		}
+	}
+	return extractors
+}
+
+// buildDisabledExtractors returns a namedExtractor for every known
+// transport that is NOT in allowedTransports. It exists purely so
+// getTokenFromRequest can tell "no key was sent" apart from "a key was
+// sent, but on a transport the operator disabled" without ever accepting
+// the token found that way.
+func buildDisabledExtractors(allowedTransports []string, headerName string) []namedExtractor {
+	if headerName == "" {
+		headerName = defaultHeaderName
+	}
+
+	allowed := make(map[string]bool, len(allowedTransports))
+	for _, t := range allowedTransports {
+		allowed[strings.ToLower(strings.TrimSpace(t))] = true
+	}
+
+	disabled := make([]namedExtractor, 0, len(allTransports))
+	for _, transport := range allTransports {
+		if allowed[transport] {
+			continue
+		}
+		disabled = append(disabled, namedExtractor{transport: transport, extract: extractorFor(transport, headerName)})
+	}
+	return disabled
+}
+
+func bearerTokenExtractor(r *authn.Request) string {
+	if r.HTTPRequest == nil {
+		return ""
+	}
+
+	header := r.HTTPRequest.Header.Get("Authorization")
+	if strings.HasPrefix(header, bearerPrefix) {
+		return strings.TrimPrefix(header, bearerPrefix)
+	}
+	return ""
+}
+
+func basicTokenExtractor(r *authn.Request) string {
+	if r.HTTPRequest == nil {
+		return ""
+	}
+
+	header := r.HTTPRequest.Header.Get("Authorization")
+	if strings.HasPrefix(header, basicPrefix) {
+		username, password, err := util.DecodeBasicAuthHeader(header)
+		if err == nil && username == "api_key" {
+			return password
+		}
+	}
+	return ""
+}
+
+// headerTokenExtractor reads the token from a configurable header, e.g.
+// X-Api-Key, for clients that cannot set Authorization.
+func headerTokenExtractor(headerName string) authn.TokenExtractor {
+	return func(r *authn.Request) string {
+		if r.HTTPRequest == nil {
+			return ""
+		}
+		return r.HTTPRequest.Header.Get(headerName)
+	}
+}
+
+// queryTokenExtractor reads the token from ?api_key=. It is opt-in only:
+// query strings end up in access logs and browser history, so operators
+// must explicitly allow it via [auth.api_key] allowed_transports.
+func queryTokenExtractor(r *authn.Request) string {
+	if r.HTTPRequest == nil {
+		return ""
+	}
+	return r.HTTPRequest.URL.Query().Get("api_key")
+}
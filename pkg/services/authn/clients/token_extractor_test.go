@@ -0,0 +1,76 @@
+package clients
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/grafana/grafana/pkg/services/authn"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildDisabledExtractors(t *testing.T) {
+	disabled := buildDisabledExtractors([]string{TransportBearer}, "")
+
+	transports := make([]string, 0, len(disabled))
+	for _, d := range disabled {
+		transports = append(transports, d.transport)
+	}
+	require.ElementsMatch(t, []string{TransportBasic, TransportHeader, TransportQuery}, transports)
+}
+
+func TestGetTokenFromRequest_DisabledTransportIsDistinguishable(t *testing.T) {
+	req := &authn.Request{HTTPRequest: &http.Request{
+		Header: http.Header{},
+		URL:    &url.URL{RawQuery: "api_key=abc123"},
+	}}
+
+	s := &APIKey{
+		tokenExtractors:    buildTokenExtractors([]string{TransportBearer}, ""),
+		disabledExtractors: buildDisabledExtractors([]string{TransportBearer}, ""),
+	}
+
+	token, disabledTransport := s.getTokenFromRequest(req)
+	require.Empty(t, token)
+	require.Equal(t, TransportQuery, disabledTransport)
+}
+
+func TestGetTokenFromRequest_NothingSent(t *testing.T) {
+	req := &authn.Request{HTTPRequest: &http.Request{
+		Header: http.Header{},
+		URL:    &url.URL{},
+	}}
+
+	s := &APIKey{
+		tokenExtractors:    buildTokenExtractors([]string{TransportBearer}, ""),
+		disabledExtractors: buildDisabledExtractors([]string{TransportBearer}, ""),
+	}
+
+	token, disabledTransport := s.getTokenFromRequest(req)
+	require.Empty(t, token)
+	require.Empty(t, disabledTransport)
+}
+
+func TestAPIKey_Test_MatchesOnDisabledTransportToo(t *testing.T) {
+	s := &APIKey{
+		tokenExtractors:    buildTokenExtractors([]string{TransportBearer}, ""),
+		disabledExtractors: buildDisabledExtractors([]string{TransportBearer}, ""),
+	}
+
+	t.Run("token on a disabled transport still matches, so Authenticate runs and can reject it explicitly", func(t *testing.T) {
+		req := &authn.Request{HTTPRequest: &http.Request{
+			Header: http.Header{},
+			URL:    &url.URL{RawQuery: "api_key=abc123"},
+		}}
+		require.True(t, s.Test(context.Background(), req))
+	})
+
+	t.Run("nothing sent on any transport does not match", func(t *testing.T) {
+		req := &authn.Request{HTTPRequest: &http.Request{
+			Header: http.Header{},
+			URL:    &url.URL{},
+		}}
+		require.False(t, s.Test(context.Background(), req))
+	})
+}
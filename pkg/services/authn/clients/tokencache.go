@@ -0,0 +1,314 @@
+package clients
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/grafana/grafana/pkg/services/apikey"
+)
+
+const (
+	defaultCachePositiveTTL = 30 * time.Second
+	defaultCacheNegativeTTL = 5 * time.Second
+	defaultCacheSize        = 5000
+	maxNegativeBackoff      = 5 * time.Minute
+)
+
+// These counters are registered once at package load rather than per
+// tokenCache instance: promauto panics on a duplicate registration to the
+// default registry, and newTokenCache can be (and in tests, is) called more
+// than once within a single process. There is only ever one live tokenCache
+// per running APIKey client, so a single un-labeled counter per metric is
+// enough — no per-instance dimension to carry as a label.
+var (
+	cacheHitsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "grafana", Subsystem: "authn_apikey_cache", Name: "hits_total",
+		Help: "Number of API key lookups served from the positive cache.",
+	})
+	cacheMissesTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "grafana", Subsystem: "authn_apikey_cache", Name: "misses_total",
+		Help: "Number of API key lookups not found in the cache.",
+	})
+	cacheNegativeHitsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "grafana", Subsystem: "authn_apikey_cache", Name: "negative_hits_total",
+		Help: "Number of API key lookups short-circuited by the negative cache.",
+	})
+	cacheEvictionsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "grafana", Subsystem: "authn_apikey_cache", Name: "evictions_total",
+		Help: "Number of positive cache entries evicted to stay under capacity.",
+	})
+	cacheNegativeEvictionsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "grafana", Subsystem: "authn_apikey_cache", Name: "negative_evictions_total",
+		Help: "Number of negative cache entries evicted to stay under capacity.",
+	})
+)
+
+// tokenCacheKey hashes a raw token into a fixed-size key. This is a plain
+// SHA-256, not the key's own bcrypt-style verification hash: the whole
+// point of the cache is to avoid paying that cost on every request, so the
+// cache key has to be cheap to derive.
+func tokenCacheKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// tokenCache holds recently verified API keys (positive entries) and
+// recent failed verification attempts per source address (negative
+// entries), sized and TTL'd separately.
+// Positive entries are evicted LRU-style once the cache is full; negative
+// entries additionally back off per source address to blunt brute-force
+// guessing.
+type tokenCache struct {
+	mu          sync.Mutex
+	capacity    int
+	positiveTTL time.Duration
+	negativeTTL time.Duration
+
+	positives     map[string]*list.Element
+	positiveOrder *list.List
+	positivesByID map[int64]string
+
+	negatives     map[string]*list.Element
+	negativeOrder *list.List
+
+	hits              prometheus.Counter
+	misses            prometheus.Counter
+	negativeHits      prometheus.Counter
+	evictions         prometheus.Counter
+	negativeEvictions prometheus.Counter
+}
+
+type positiveEntry struct {
+	key     string
+	apiKey  *apikey.APIKey
+	expires time.Time
+}
+
+// negativeEntry tracks repeated failed verification attempts from a single
+// source address, independent of which (wrong) token each attempt guessed:
+// a real brute-force guesser sends a different token on every request, so
+// keying this by token instead of source would give every guess its own
+// fresh entry and never back off at all.
+type negativeEntry struct {
+	source  string
+	expires time.Time
+	count   int
+}
+
+func newTokenCache(capacity int, positiveTTL, negativeTTL time.Duration) *tokenCache {
+	if capacity <= 0 {
+		capacity = defaultCacheSize
+	}
+	if positiveTTL <= 0 {
+		positiveTTL = defaultCachePositiveTTL
+	}
+	if negativeTTL <= 0 {
+		negativeTTL = defaultCacheNegativeTTL
+	}
+
+	return &tokenCache{
+		capacity:          capacity,
+		positiveTTL:       positiveTTL,
+		negativeTTL:       negativeTTL,
+		positives:         make(map[string]*list.Element),
+		positiveOrder:     list.New(),
+		positivesByID:     make(map[int64]string),
+		negatives:         make(map[string]*list.Element),
+		negativeOrder:     list.New(),
+		hits:              cacheHitsTotal,
+		misses:            cacheMissesTotal,
+		negativeHits:      cacheNegativeHitsTotal,
+		evictions:         cacheEvictionsTotal,
+		negativeEvictions: cacheNegativeEvictionsTotal,
+	}
+}
+
+// get returns the cached key for token, re-validating its Expires field
+// rather than trusting the TTL alone: a key can expire well before its
+// cache entry would, and the cache must not grant access past that point.
+func (c *tokenCache) get(token string) (*apikey.APIKey, bool) {
+	key := tokenCacheKey(token)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.positives[key]
+	if !ok {
+		c.misses.Inc()
+		return nil, false
+	}
+
+	entry := el.Value.(*positiveEntry)
+	now := time.Now()
+	if now.After(entry.expires) {
+		c.removePositiveLocked(el)
+		c.misses.Inc()
+		return nil, false
+	}
+	if entry.apiKey.Expires != nil && *entry.apiKey.Expires <= now.Unix() {
+		c.removePositiveLocked(el)
+		c.misses.Inc()
+		return nil, false
+	}
+
+	c.positiveOrder.MoveToFront(el)
+	c.hits.Inc()
+	return entry.apiKey, true
+}
+
+func (c *tokenCache) set(token string, key *apikey.APIKey) {
+	cacheKey := tokenCacheKey(token)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.positives[cacheKey]; ok {
+		el.Value.(*positiveEntry).apiKey = key
+		el.Value.(*positiveEntry).expires = time.Now().Add(c.positiveTTL)
+		c.positiveOrder.MoveToFront(el)
+		return
+	}
+
+	el := c.positiveOrder.PushFront(&positiveEntry{
+		key:     cacheKey,
+		apiKey:  key,
+		expires: time.Now().Add(c.positiveTTL),
+	})
+	c.positives[cacheKey] = el
+	c.positivesByID[key.Id] = cacheKey
+
+	for c.positiveOrder.Len() > c.capacity {
+		c.removePositiveLocked(c.positiveOrder.Back())
+		c.evictions.Inc()
+	}
+}
+
+func (c *tokenCache) removePositiveLocked(el *list.Element) {
+	entry := el.Value.(*positiveEntry)
+	c.positiveOrder.Remove(el)
+	delete(c.positives, entry.key)
+	if entry.apiKey != nil {
+		delete(c.positivesByID, entry.apiKey.Id)
+	}
+}
+
+// invalidate drops the positive cache entry for token, called when the
+// underlying key is revoked or deleted so the cache never outlives the key
+// it's for. There is no equivalent negative-cache entry to drop: that
+// cache is keyed by source address, not by token, so a valid token being
+// revoked has no bearing on it.
+func (c *tokenCache) invalidate(token string) {
+	key := tokenCacheKey(token)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.positives[key]; ok {
+		c.removePositiveLocked(el)
+	}
+}
+
+// invalidateByID drops the positive entry for keyID, if cached. It exists
+// because apikey.MutationEvent identifies the key by ID, not by the raw
+// token used to derive a cache key: OnMutation subscribers only ever learn
+// a key changed, never the token that verifies it.
+func (c *tokenCache) invalidateByID(keyID int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cacheKey, ok := c.positivesByID[keyID]
+	if !ok {
+		return
+	}
+	if el, ok := c.positives[cacheKey]; ok {
+		c.removePositiveLocked(el)
+	}
+}
+
+func (c *tokenCache) removeNegativeLocked(el *list.Element) {
+	entry := el.Value.(*negativeEntry)
+	c.negativeOrder.Remove(el)
+	delete(c.negatives, entry.source)
+}
+
+// isBlocked reports whether source should be short-circuited as invalid
+// without re-checking storage, and extends its backoff further: a steady
+// stream of requests from a still-blocked source keeps pushing its expiry
+// out rather than letting it quietly recover mid-block.
+func (c *tokenCache) isBlocked(source string) bool {
+	if source == "" {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.negatives[source]
+	if !ok {
+		return false
+	}
+	entry := el.Value.(*negativeEntry)
+	if time.Now().After(entry.expires) {
+		c.removeNegativeLocked(el)
+		return false
+	}
+
+	c.negativeOrder.MoveToFront(el)
+	c.negativeHits.Inc()
+	entry.count++
+	entry.expires = c.backoffExpiry(entry.count)
+	return true
+}
+
+// recordInvalid starts or extends the negative cache entry for source,
+// backing off per source address so a single guesser pays an increasing
+// penalty regardless of how many distinct (wrong) tokens it cycles
+// through, while other clients at different addresses are unaffected. Like
+// the positive cache, entries are capped at c.capacity and evicted
+// LRU-style: without a bound here, a stream of distinct source addresses
+// would otherwise grow it forever. A source left empty (the caller
+// couldn't determine one) is never recorded, so unattributed attempts
+// don't pile into one shared bucket.
+func (c *tokenCache) recordInvalid(source string) {
+	if source == "" {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.negatives[source]; ok {
+		entry := el.Value.(*negativeEntry)
+		entry.count++
+		entry.expires = c.backoffExpiry(entry.count)
+		c.negativeOrder.MoveToFront(el)
+		return
+	}
+
+	entry := &negativeEntry{source: source, count: 1}
+	entry.expires = c.backoffExpiry(1)
+	el := c.negativeOrder.PushFront(entry)
+	c.negatives[source] = el
+
+	for c.negativeOrder.Len() > c.capacity {
+		c.removeNegativeLocked(c.negativeOrder.Back())
+		c.negativeEvictions.Inc()
+	}
+}
+
+func (c *tokenCache) backoffExpiry(attempts int) time.Time {
+	backoff := c.negativeTTL
+	for i := 1; i < attempts && backoff < maxNegativeBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxNegativeBackoff {
+		backoff = maxNegativeBackoff
+	}
+	return time.Now().Add(backoff)
+}
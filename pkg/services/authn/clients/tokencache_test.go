@@ -0,0 +1,151 @@
+package clients
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/apikey"
+)
+
+func TestTokenCache_PositiveGetSet(t *testing.T) {
+	c := newTokenCache(10, time.Minute, time.Minute)
+
+	_, ok := c.get("tok1")
+	require.False(t, ok)
+
+	key := &apikey.APIKey{Id: 1}
+	c.set("tok1", key)
+
+	got, ok := c.get("tok1")
+	require.True(t, ok)
+	require.Same(t, key, got)
+}
+
+func TestTokenCache_PositiveTTLExpires(t *testing.T) {
+	c := newTokenCache(10, time.Millisecond, time.Minute)
+
+	c.set("tok1", &apikey.APIKey{Id: 1})
+	time.Sleep(5 * time.Millisecond)
+
+	_, ok := c.get("tok1")
+	require.False(t, ok)
+}
+
+func TestTokenCache_KeyExpiryOverridesCacheTTL(t *testing.T) {
+	c := newTokenCache(10, time.Minute, time.Minute)
+
+	expired := time.Now().Add(-time.Second).Unix()
+	c.set("tok1", &apikey.APIKey{Id: 1, Expires: &expired})
+
+	_, ok := c.get("tok1")
+	require.False(t, ok, "a cached key that has itself expired must not be served, even within the cache TTL")
+}
+
+func TestTokenCache_PositiveLRUEviction(t *testing.T) {
+	c := newTokenCache(2, time.Minute, time.Minute)
+
+	c.set("tok1", &apikey.APIKey{Id: 1})
+	c.set("tok2", &apikey.APIKey{Id: 2})
+	c.set("tok3", &apikey.APIKey{Id: 3}) // evicts tok1, the least recently used
+
+	_, ok := c.get("tok1")
+	require.False(t, ok)
+	_, ok = c.get("tok2")
+	require.True(t, ok)
+	_, ok = c.get("tok3")
+	require.True(t, ok)
+}
+
+func TestTokenCache_NegativeLRUEviction(t *testing.T) {
+	c := newTokenCache(2, time.Minute, time.Minute)
+
+	c.recordInvalid("src1")
+	c.recordInvalid("src2")
+	c.recordInvalid("src3") // must evict src1, not grow unbounded
+
+	require.False(t, c.isBlocked("src1"))
+	require.True(t, c.isBlocked("src2"))
+	require.True(t, c.isBlocked("src3"))
+	require.LessOrEqual(t, c.negativeOrder.Len(), 2)
+}
+
+func TestTokenCache_NegativeUnboundedStreamStaysCapped(t *testing.T) {
+	c := newTokenCache(50, time.Minute, time.Minute)
+
+	for i := 0; i < 1000; i++ {
+		c.recordInvalid(fmt.Sprintf("attacker-%d", i))
+	}
+
+	require.LessOrEqual(t, len(c.negatives), 50)
+	require.Equal(t, len(c.negatives), c.negativeOrder.Len())
+}
+
+func TestTokenCache_NegativeBackoff(t *testing.T) {
+	c := newTokenCache(10, time.Minute, time.Second)
+
+	c.recordInvalid("src")
+	require.True(t, c.isBlocked("src"))
+
+	entry := c.negatives["src"].Value.(*negativeEntry)
+	firstExpiry := entry.expires
+
+	require.True(t, c.isBlocked("src"))
+	require.True(t, entry.expires.After(firstExpiry), "repeated attempts from the same source must back off, extending expiry")
+}
+
+func TestTokenCache_NegativeBackoff_AccumulatesAcrossDistinctTokenGuesses(t *testing.T) {
+	c := newTokenCache(10, time.Minute, time.Second)
+
+	// A real brute-force guesser sends a different wrong token on every
+	// request; the cache is keyed by source address, not by token, so the
+	// backoff count must still accumulate instead of resetting each time.
+	c.recordInvalid("attacker")
+	c.recordInvalid("attacker")
+	c.recordInvalid("attacker")
+
+	entry := c.negatives["attacker"].Value.(*negativeEntry)
+	require.Equal(t, 3, entry.count)
+}
+
+func TestTokenCache_NegativeBackoff_EmptySourceNeverRecordedOrBlocked(t *testing.T) {
+	c := newTokenCache(10, time.Minute, time.Second)
+
+	c.recordInvalid("")
+	require.False(t, c.isBlocked(""))
+	require.Empty(t, c.negatives)
+}
+
+func TestTokenCache_Invalidate(t *testing.T) {
+	c := newTokenCache(10, time.Minute, time.Minute)
+	c.set("tok1", &apikey.APIKey{Id: 1})
+
+	c.invalidate("tok1")
+
+	_, ok := c.get("tok1")
+	require.False(t, ok)
+}
+
+func TestTokenCache_InvalidateByID(t *testing.T) {
+	c := newTokenCache(10, time.Minute, time.Minute)
+	c.set("tok1", &apikey.APIKey{Id: 42})
+
+	c.invalidateByID(42)
+
+	_, ok := c.get("tok1")
+	require.False(t, ok, "a revoked key's cached positive entry must be dropped even though the caller only knows the key ID")
+	_, ok = c.positivesByID[42]
+	require.False(t, ok)
+}
+
+func TestTokenCache_InvalidateByID_UnknownIDIsNoop(t *testing.T) {
+	c := newTokenCache(10, time.Minute, time.Minute)
+	c.set("tok1", &apikey.APIKey{Id: 1})
+
+	c.invalidateByID(999)
+
+	_, ok := c.get("tok1")
+	require.True(t, ok)
+}
@@ -0,0 +1,130 @@
+// Package lastused implements a batched, debounced writer for "last used"
+// timestamps, shared by the authn clients that authenticate tokens on every
+// request (API keys, personal access tokens) and would otherwise each spawn
+// a goroutine and an individual UPDATE per request.
+package lastused
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+)
+
+const bufferSize = 4096
+
+// The counters below are registered once at package load, labeled by
+// subsystem, rather than per Recorder instance: promauto panics on a
+// duplicate registration to the default registry, and NewRecorder can be
+// (and in tests, is) called more than once for the same or different
+// subsystems within a single process.
+var (
+	queuedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "grafana",
+		Subsystem: "authn_lastused",
+		Name:      "queued_total",
+		Help:      "Number of last-used updates queued for batching.",
+	}, []string{"subsystem"})
+
+	flushedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "grafana",
+		Subsystem: "authn_lastused",
+		Name:      "flushed_total",
+		Help:      "Number of last-used updates written to storage.",
+	}, []string{"subsystem"})
+
+	droppedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "grafana",
+		Subsystem: "authn_lastused",
+		Name:      "dropped_total",
+		Help:      "Number of last-used updates dropped because the buffer was full.",
+	}, []string{"subsystem"})
+)
+
+// FlushFunc persists the deduplicated set of ids collected during one
+// window, typically as a single `UPDATE ... WHERE id IN (...)` statement.
+type FlushFunc func(ctx context.Context, ids []int64) error
+
+// Recorder coalesces per-id "used now" events within a configurable window
+// and flushes them in a single batch, instead of one write per event.
+type Recorder struct {
+	log    log.Logger
+	window time.Duration
+	flush  FlushFunc
+	events chan int64
+
+	queued  prometheus.Counter
+	flushed prometheus.Counter
+	dropped prometheus.Counter
+}
+
+// NewRecorder builds a Recorder for the given subsystem (used as a metrics
+// label, e.g. "api_key" or "pat"). window bounds how long an id can sit
+// before it is written; flush performs the actual batch write.
+func NewRecorder(subsystem string, window time.Duration, flush FlushFunc) *Recorder {
+	return &Recorder{
+		log:     log.New("authn.lastused", "subsystem", subsystem),
+		window:  window,
+		flush:   flush,
+		events:  make(chan int64, bufferSize),
+		queued:  queuedTotal.WithLabelValues(subsystem),
+		flushed: flushedTotal.WithLabelValues(subsystem),
+		dropped: droppedTotal.WithLabelValues(subsystem),
+	}
+}
+
+// Record marks id as used now. It never blocks: once the buffer is full,
+// further updates are dropped and counted rather than slowing down the
+// request that triggered them.
+func (r *Recorder) Record(id int64) {
+	select {
+	case r.events <- id:
+		r.queued.Inc()
+	default:
+		r.dropped.Inc()
+		r.log.Warn("dropping last-used update, buffer is full", "id", id)
+	}
+}
+
+// Run drains events into per-window batches and flushes each batch with a
+// single call to FlushFunc. It satisfies the background-services registry
+// convention and returns once ctx is done, flushing whatever is pending.
+func (r *Recorder) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.window)
+	defer ticker.Stop()
+
+	pending := map[int64]struct{}{}
+
+	for {
+		select {
+		case id := <-r.events:
+			pending[id] = struct{}{}
+		case <-ticker.C:
+			r.flushPending(ctx, pending)
+			pending = map[int64]struct{}{}
+		case <-ctx.Done():
+			r.flushPending(context.Background(), pending)
+			return ctx.Err()
+		}
+	}
+}
+
+func (r *Recorder) flushPending(ctx context.Context, pending map[int64]struct{}) {
+	if len(pending) == 0 {
+		return
+	}
+
+	ids := make([]int64, 0, len(pending))
+	for id := range pending {
+		ids = append(ids, id)
+	}
+
+	if err := r.flush(ctx, ids); err != nil {
+		r.log.Warn("failed to flush last-used updates", "count", len(ids), "err", err)
+		return
+	}
+	r.flushed.Add(float64(len(ids)))
+}
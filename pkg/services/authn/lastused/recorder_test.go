@@ -0,0 +1,78 @@
+package lastused
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestRecorder_BatchesAndDedupsWithinAWindow exercises the property the
+// review comment for chunk0-4 called out: Record events within a single
+// window collapse into one FlushFunc call carrying the deduplicated ids,
+// not one write per Record.
+func TestRecorder_BatchesAndDedupsWithinAWindow(t *testing.T) {
+	var mu sync.Mutex
+	var calls [][]int64
+
+	flush := func(ctx context.Context, ids []int64) error {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, ids)
+		return nil
+	}
+
+	r := NewRecorder("test", 20*time.Millisecond, flush)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- r.Run(ctx) }()
+
+	r.Record(1)
+	r.Record(2)
+	r.Record(1) // duplicate within the same window
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(calls) >= 1
+	}, time.Second, time.Millisecond)
+
+	cancel()
+	<-done
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.NotEmpty(t, calls)
+	require.ElementsMatch(t, []int64{1, 2}, calls[0])
+}
+
+func TestRecorder_FlushesPendingOnShutdown(t *testing.T) {
+	var mu sync.Mutex
+	var calls [][]int64
+
+	flush := func(ctx context.Context, ids []int64) error {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, ids)
+		return nil
+	}
+
+	// A window longer than the test so the only flush comes from shutdown.
+	r := NewRecorder("test", time.Hour, flush)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- r.Run(ctx) }()
+
+	r.Record(42)
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	<-done
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal(t, [][]int64{{42}}, calls)
+}
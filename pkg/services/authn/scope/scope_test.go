@@ -0,0 +1,104 @@
+package scope
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseScopes(t *testing.T) {
+	t.Run("nil input means unrestricted", func(t *testing.T) {
+		scopes, err := ParseScopes(nil)
+		require.NoError(t, err)
+		require.Nil(t, scopes)
+	})
+
+	t.Run("parses resource and target", func(t *testing.T) {
+		scopes, err := ParseScopes([]string{"datasources:read", "dashboards:write:uid/abc"})
+		require.NoError(t, err)
+		require.Equal(t, []Scope{
+			{Resource: "datasources", Target: "read"},
+			{Resource: "dashboards", Target: "write:uid/abc"},
+		}, scopes)
+	})
+
+	t.Run("rejects empty resource", func(t *testing.T) {
+		_, err := ParseScopes([]string{":read"})
+		require.Error(t, err)
+	})
+}
+
+func TestVerify(t *testing.T) {
+	ctx := context.Background()
+	req := &http.Request{}
+
+	t.Run("nil scopes are unrestricted", func(t *testing.T) {
+		ok, err := Verify(ctx, nil, Scope{Resource: "datasources", Target: "read"}, req)
+		require.NoError(t, err)
+		require.True(t, ok)
+	})
+
+	t.Run("matches exact target", func(t *testing.T) {
+		held := []Scope{{Resource: "dashboards", Target: "write:uid/abc"}}
+		ok, err := Verify(ctx, held, Scope{Resource: "dashboards", Target: "write:uid/abc"}, req)
+		require.NoError(t, err)
+		require.True(t, ok)
+	})
+
+	t.Run("rejects mismatched target", func(t *testing.T) {
+		held := []Scope{{Resource: "dashboards", Target: "write:uid/abc"}}
+		ok, err := Verify(ctx, held, Scope{Resource: "dashboards", Target: "write:uid/def"}, req)
+		require.NoError(t, err)
+		require.False(t, ok)
+	})
+
+	t.Run("wildcard target (no Target) matches any target", func(t *testing.T) {
+		held := []Scope{{Resource: "dashboards"}}
+		ok, err := Verify(ctx, held, Scope{Resource: "dashboards", Target: "write:uid/def"}, req)
+		require.NoError(t, err)
+		require.True(t, ok)
+	})
+
+	t.Run("rejects missing resource", func(t *testing.T) {
+		held := []Scope{{Resource: "dashboards"}}
+		ok, err := Verify(ctx, held, Scope{Resource: "orgs", Target: "1"}, req)
+		require.NoError(t, err)
+		require.False(t, ok)
+	})
+
+	t.Run("consults a registered verifier for its resource", func(t *testing.T) {
+		t.Cleanup(func() { delete(verifiers, "custom") })
+		RegisterVerifier("custom", func(ctx context.Context, held, required Scope, r *http.Request) (bool, error) {
+			return held.Target == "allow", nil
+		})
+
+		ok, err := Verify(ctx, []Scope{{Resource: "custom", Target: "allow"}}, Scope{Resource: "custom", Target: "anything"}, req)
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		ok, err = Verify(ctx, []Scope{{Resource: "custom", Target: "deny"}}, Scope{Resource: "custom", Target: "anything"}, req)
+		require.NoError(t, err)
+		require.False(t, ok)
+	})
+}
+
+func TestSubset(t *testing.T) {
+	t.Run("anything is a subset of an unrestricted parent", func(t *testing.T) {
+		child := []Scope{{Resource: "dashboards", Target: "write:uid/abc"}}
+		require.True(t, Subset(child, nil))
+	})
+
+	t.Run("child covered by parent", func(t *testing.T) {
+		child := []Scope{{Resource: "dashboards", Target: "write:uid/abc"}}
+		parent := []Scope{{Resource: "dashboards"}}
+		require.True(t, Subset(child, parent))
+	})
+
+	t.Run("child not covered by parent", func(t *testing.T) {
+		child := []Scope{{Resource: "dashboards", Target: "write:uid/abc"}}
+		parent := []Scope{{Resource: "datasources", Target: "read"}}
+		require.False(t, Subset(child, parent))
+	})
+}
@@ -0,0 +1,7 @@
+package authn
+
+// TokenExtractor pulls a bearer-style token out of an incoming request. It
+// returns the empty string when the request does not carry a token in the
+// transport the extractor looks at, so that callers can chain several
+// extractors and take the first non-empty result.
+type TokenExtractor func(r *Request) string
@@ -0,0 +1,42 @@
+package pat
+
+import (
+	"context"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+)
+
+// AuditRecorder records the lifecycle events the request asks for: created,
+// used, and revoked. Use is recorded once per lastused.Recorder flush rather
+// than per request, matching the batching UpdateLastUsedDates already does,
+// so a hot token doesn't produce an audit event per request.
+type AuditRecorder interface {
+	RecordCreated(ctx context.Context, tok *Token)
+	RecordRevoked(ctx context.Context, userID, tokenID int64)
+	RecordUsed(ctx context.Context, tokenIDs []int64)
+}
+
+// LogAuditRecorder writes audit events to the application log. It stands in
+// for the structured audit/annotation sink the rest of Grafana emits to,
+// which isn't part of this tree.
+type LogAuditRecorder struct {
+	log log.Logger
+}
+
+func ProvideLogAuditRecorder() *LogAuditRecorder {
+	return &LogAuditRecorder{log: log.New("pat.audit")}
+}
+
+var _ AuditRecorder = (*LogAuditRecorder)(nil)
+
+func (r *LogAuditRecorder) RecordCreated(ctx context.Context, tok *Token) {
+	r.log.Info("personal access token created", "tokenID", tok.ID, "userID", tok.UserID, "name", tok.Name)
+}
+
+func (r *LogAuditRecorder) RecordRevoked(ctx context.Context, userID, tokenID int64) {
+	r.log.Info("personal access token revoked", "tokenID", tokenID, "userID", userID)
+}
+
+func (r *LogAuditRecorder) RecordUsed(ctx context.Context, tokenIDs []int64) {
+	r.log.Info("personal access tokens used", "tokenIDs", tokenIDs)
+}
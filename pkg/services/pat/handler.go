@@ -0,0 +1,139 @@
+package pat
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/grafana/grafana/pkg/services/authn"
+	"github.com/grafana/grafana/pkg/services/authn/scope"
+)
+
+// createTokenRequest is the POST /api/user/tokens body.
+type createTokenRequest struct {
+	Name          string   `json:"name"`
+	Description   string   `json:"description"`
+	Scopes        []string `json:"scopes"`
+	AllowedIPs    []string `json:"allowedIPs"`
+	SecondsToLive *int64   `json:"secondsToLive"`
+}
+
+// Handler backs the /api/user/tokens surface: a signed-in user manages
+// their own personal access tokens, minting new ones and revoking existing
+// ones. It never returns Hash, only the plaintext secret at creation time.
+type Handler struct {
+	service Service
+}
+
+func ProvideHandler(service Service) *Handler {
+	return &Handler{service: service}
+}
+
+// CreateToken handles POST /api/user/tokens. As with apikey.Handler, the
+// requested scopes are validated as a subset of the caller's own scopes
+// before minting: a user can never delegate a token more powerful than
+// their own session.
+func (h *Handler) CreateToken(w http.ResponseWriter, r *http.Request) {
+	identity, userID, ok := h.callerUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var req createTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	requested, err := scope.ParseScopes(req.Scopes)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !scope.Subset(requested, identity.Scopes) {
+		http.Error(w, "requested scopes exceed the caller's own scopes", http.StatusForbidden)
+		return
+	}
+
+	cmd := &CreateTokenCommand{
+		UserID:      userID,
+		Name:        req.Name,
+		Description: req.Description,
+		Scopes:      req.Scopes,
+		AllowedIPs:  req.AllowedIPs,
+	}
+	if req.SecondsToLive != nil {
+		expires := time.Now().Add(time.Duration(*req.SecondsToLive) * time.Second)
+		cmd.ExpiresAt = &expires
+	}
+
+	tok, secret, err := h.service.CreateToken(r.Context(), cmd)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"id":    tok.ID,
+		"name":  tok.Name,
+		"token": secret,
+	})
+}
+
+// ListTokens handles GET /api/user/tokens, returning the caller's own
+// tokens. Hash is never included in the response.
+func (h *Handler) ListTokens(w http.ResponseWriter, r *http.Request) {
+	_, userID, ok := h.callerUserID(w, r)
+	if !ok {
+		return
+	}
+
+	tokens, err := h.service.ListTokens(r.Context(), userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(tokens)
+}
+
+// RevokeToken handles DELETE /api/user/tokens/{id}.
+func (h *Handler) RevokeToken(w http.ResponseWriter, r *http.Request, idParam string) {
+	_, userID, ok := h.callerUserID(w, r)
+	if !ok {
+		return
+	}
+
+	tokenID, err := strconv.ParseInt(idParam, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid token id", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.RevokeToken(r.Context(), userID, tokenID); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// callerUserID resolves the signed-in identity attached to r and its
+// numeric user id, writing an error response and returning ok=false if
+// there isn't one or it isn't a user (e.g. an API key can't own PATs).
+func (h *Handler) callerUserID(w http.ResponseWriter, r *http.Request) (*authn.Identity, int64, bool) {
+	identity, ok := authn.IdentityFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthenticated", http.StatusUnauthorized)
+		return nil, 0, false
+	}
+
+	namespace, id, err := authn.ParseNamespacedID(identity.ID)
+	if err != nil || namespace != authn.NamespaceUser {
+		http.Error(w, "personal access tokens can only be managed by a signed-in user", http.StatusForbidden)
+		return nil, 0, false
+	}
+	return identity, id, true
+}
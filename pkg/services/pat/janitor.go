@@ -0,0 +1,52 @@
+package pat
+
+import (
+	"context"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+)
+
+// janitorInterval is how often ProvideJanitor sweeps for expired tokens.
+const janitorInterval = time.Hour
+
+// Janitor periodically deletes personal access tokens that expired more
+// than a day ago, keeping the table from growing unbounded with stale
+// short-lived tokens. It is registered as a background service the same
+// way other Grafana housekeeping jobs are.
+type Janitor struct {
+	log     log.Logger
+	service Service
+}
+
+func ProvideJanitor(service Service) *Janitor {
+	return &Janitor{
+		log:     log.New("pat.janitor"),
+		service: service,
+	}
+}
+
+func (j *Janitor) Run(ctx context.Context) error {
+	ticker := time.NewTicker(janitorInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			j.sweep(ctx)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (j *Janitor) sweep(ctx context.Context) {
+	n, err := j.service.DeleteExpired(ctx, time.Now().Add(-24*time.Hour))
+	if err != nil {
+		j.log.Warn("failed to delete expired access tokens", "err", err)
+		return
+	}
+	if n > 0 {
+		j.log.Debug("deleted expired access tokens", "count", n)
+	}
+}
@@ -0,0 +1,30 @@
+package pat
+
+import (
+	"github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+)
+
+// AddMigrations registers the personal_access_token table this package
+// depends on. It is called from the main migration list the same way every
+// other service's AddMigrations is.
+func AddMigrations(mg *migrator.Migrator) {
+	mg.AddMigration("create personal_access_token table", migrator.NewAddTableMigration(migrator.Table{
+		Name: "personal_access_token",
+		Columns: []*migrator.Column{
+			{Name: "id", Type: migrator.DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "user_id", Type: migrator.DB_BigInt, Nullable: false},
+			{Name: "name", Type: migrator.DB_NVarchar, Length: 190, Nullable: false},
+			{Name: "description", Type: migrator.DB_Text, Nullable: true},
+			{Name: "hash", Type: migrator.DB_NVarchar, Length: 128, Nullable: false},
+			{Name: "scopes", Type: migrator.DB_Text, Nullable: true},
+			{Name: "allowed_ips", Type: migrator.DB_Text, Nullable: true},
+			{Name: "expires_at", Type: migrator.DB_DateTime, Nullable: true},
+			{Name: "last_used_at", Type: migrator.DB_DateTime, Nullable: true},
+			{Name: "created", Type: migrator.DB_DateTime, Nullable: false},
+		},
+		Indices: []*migrator.Index{
+			{Cols: []string{"user_id"}},
+			{Cols: []string{"hash"}, Type: migrator.UniqueIndex},
+		},
+	}))
+}
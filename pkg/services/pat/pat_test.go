@@ -0,0 +1,66 @@
+package pat
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestToken_Expired(t *testing.T) {
+	now := time.Now()
+
+	t.Run("no expiry never expires", func(t *testing.T) {
+		tok := &Token{}
+		require.False(t, tok.Expired(now))
+	})
+
+	t.Run("future expiry is not expired", func(t *testing.T) {
+		expires := now.Add(time.Hour)
+		tok := &Token{ExpiresAt: &expires}
+		require.False(t, tok.Expired(now))
+	})
+
+	t.Run("past expiry is expired", func(t *testing.T) {
+		expires := now.Add(-time.Hour)
+		tok := &Token{ExpiresAt: &expires}
+		require.True(t, tok.Expired(now))
+	})
+}
+
+func TestToken_IPAllowed(t *testing.T) {
+	t.Run("no restriction allows any address", func(t *testing.T) {
+		tok := &Token{}
+		require.True(t, tok.IPAllowed(net.ParseIP("203.0.113.5")))
+	})
+
+	t.Run("matches a configured CIDR", func(t *testing.T) {
+		tok := &Token{AllowedIPs: []string{"203.0.113.0/24"}}
+		require.True(t, tok.IPAllowed(net.ParseIP("203.0.113.5")))
+	})
+
+	t.Run("rejects an address outside every CIDR", func(t *testing.T) {
+		tok := &Token{AllowedIPs: []string{"203.0.113.0/24"}}
+		require.False(t, tok.IPAllowed(net.ParseIP("198.51.100.5")))
+	})
+}
+
+func TestHashToken(t *testing.T) {
+	t.Run("hashes a glpat_-prefixed secret deterministically", func(t *testing.T) {
+		secret := Prefix + "abc123"
+
+		hash, err := HashToken(secret)
+		require.NoError(t, err)
+		require.NotEmpty(t, hash)
+
+		again, err := HashToken(secret)
+		require.NoError(t, err)
+		require.Equal(t, hash, again)
+	})
+
+	t.Run("rejects a secret without the pat prefix", func(t *testing.T) {
+		_, err := HashToken("glapikey_abc123")
+		require.ErrorIs(t, err, ErrInvalidToken)
+	})
+}
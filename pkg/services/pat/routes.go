@@ -0,0 +1,34 @@
+package pat
+
+import (
+	"net/http"
+	"strings"
+)
+
+// RegisterRoutes mounts the /api/user/tokens surface. Unlike apikey's
+// RegisterRoutes, this isn't gated by accesscontrol.RequireScope: PATs are
+// always managed as the caller's own user, never delegated, so
+// callerUserID's namespace check is the only authorization these routes
+// need — there is no coarser "can this identity touch tokens at all"
+// question to ask first.
+func RegisterRoutes(mux *http.ServeMux, h *Handler) {
+	mux.Handle("/api/user/tokens", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			h.CreateToken(w, r)
+		case http.MethodGet:
+			h.ListTokens(w, r)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	mux.Handle("/api/user/tokens/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		idParam := strings.TrimPrefix(r.URL.Path, "/api/user/tokens/")
+		h.RevokeToken(w, r, idParam)
+	}))
+}
@@ -0,0 +1,149 @@
+package pat
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// InMemoryStore is a reference Service implementation backed by a map
+// rather than the database, mirroring apikey.InMemoryStore. It is enough to
+// exercise the PAT authn client and the /api/user/tokens surface end to
+// end; a SQL-backed store would read/write the personal_access_token table
+// added by the migration in migrations.go and otherwise behave identically.
+type InMemoryStore struct {
+	mu      sync.Mutex
+	nextID  int64
+	byID    map[int64]*Token
+	byHash  map[string]int64
+	auditor AuditRecorder
+}
+
+func ProvideInMemoryStore(auditor AuditRecorder) *InMemoryStore {
+	return &InMemoryStore{
+		byID:    map[int64]*Token{},
+		byHash:  map[string]int64{},
+		auditor: auditor,
+	}
+}
+
+var _ Service = (*InMemoryStore)(nil)
+
+func (s *InMemoryStore) CreateToken(ctx context.Context, cmd *CreateTokenCommand) (*Token, string, error) {
+	secret, hashed, err := generateToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	s.mu.Lock()
+	s.nextID++
+	tok := &Token{
+		ID:          s.nextID,
+		UserID:      cmd.UserID,
+		Name:        cmd.Name,
+		Description: cmd.Description,
+		Hash:        hashed,
+		Scopes:      cmd.Scopes,
+		AllowedIPs:  cmd.AllowedIPs,
+		ExpiresAt:   cmd.ExpiresAt,
+		Created:     time.Now(),
+	}
+	s.byID[tok.ID] = tok
+	s.byHash[hashed] = tok.ID
+	s.mu.Unlock()
+
+	if s.auditor != nil {
+		s.auditor.RecordCreated(ctx, tok)
+	}
+	return tok, secret, nil
+}
+
+func (s *InMemoryStore) ListTokens(ctx context.Context, userID int64) ([]*Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tokens := make([]*Token, 0)
+	for _, tok := range s.byID {
+		if tok.UserID == userID {
+			tokens = append(tokens, tok)
+		}
+	}
+	return tokens, nil
+}
+
+func (s *InMemoryStore) RevokeToken(ctx context.Context, userID, tokenID int64) error {
+	s.mu.Lock()
+	tok, ok := s.byID[tokenID]
+	if !ok || tok.UserID != userID {
+		s.mu.Unlock()
+		return ErrInvalidToken
+	}
+	delete(s.byID, tokenID)
+	delete(s.byHash, tok.Hash)
+	s.mu.Unlock()
+
+	if s.auditor != nil {
+		s.auditor.RecordRevoked(ctx, userID, tokenID)
+	}
+	return nil
+}
+
+func (s *InMemoryStore) GetTokenByHash(ctx context.Context, hash string) (*Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id, ok := s.byHash[hash]
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	return s.byID[id], nil
+}
+
+func (s *InMemoryStore) UpdateLastUsedDates(ctx context.Context, tokenIDs []int64) error {
+	s.mu.Lock()
+	for _, id := range tokenIDs {
+		if tok, ok := s.byID[id]; ok {
+			now := time.Now()
+			tok.LastUsedAt = &now
+		}
+	}
+	s.mu.Unlock()
+
+	if s.auditor != nil && len(tokenIDs) > 0 {
+		s.auditor.RecordUsed(ctx, tokenIDs)
+	}
+	return nil
+}
+
+func (s *InMemoryStore) DeleteExpired(ctx context.Context, olderThan time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var n int64
+	for id, tok := range s.byID {
+		if tok.ExpiresAt != nil && tok.ExpiresAt.Before(olderThan) {
+			delete(s.byID, id)
+			delete(s.byHash, tok.Hash)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// generateToken mints a new glpat_-prefixed secret and returns both the
+// secret to hand back to the caller once and the hash to persist.
+func generateToken() (secret, hashed string, err error) {
+	raw := make([]byte, 24)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", err
+	}
+	secret = Prefix + hex.EncodeToString(raw)
+
+	hashed, err = HashToken(secret)
+	if err != nil {
+		return "", "", err
+	}
+	return secret, hashed, nil
+}
@@ -0,0 +1,73 @@
+// Package setting holds Grafana's runtime configuration, loaded from
+// grafana.ini (and environment overrides) into Cfg.
+package setting
+
+import (
+	"strings"
+	"time"
+
+	"gopkg.in/ini.v1"
+)
+
+// Cfg is Grafana's runtime configuration. Only the fields the authn API-key
+// and PAT clients depend on are declared here; the rest of Grafana's
+// configuration surface lives alongside them in the same struct.
+type Cfg struct {
+	// TrustedProxies lists the proxy addresses/CIDRs whose X-Forwarded-For
+	// header is trusted when resolving a request's real client IP, used by
+	// AllowedIPs enforcement. Read from [auth.api_key] trusted_proxies.
+	TrustedProxies []string
+
+	// APIKeyAllowedTransports lists the token transports (see
+	// clients.Transport*) the api_key and pat authn clients accept a token
+	// on, read from [auth.api_key] allowed_transports. Empty means the
+	// clients fall back to their own default (bearer + basic).
+	APIKeyAllowedTransports []string
+
+	// APIKeyHeaderName is the header name the "header" transport reads a
+	// token from, read from [auth.api_key] header_name.
+	APIKeyHeaderName string
+
+	// APIKeyCacheSize bounds the number of positive and negative entries the
+	// verified-token cache holds, read from [auth.api_key] cache_size.
+	APIKeyCacheSize int
+
+	// APIKeyCachePositiveTTL bounds how long a verified token is served out
+	// of the cache before being re-checked against storage.
+	// APIKeyCacheNegativeTTL is how long a source address is refused without
+	// a storage check after a failed verification; each further failure from
+	// that source doubles it, up to a fixed cap. Read from [auth.api_key]
+	// cache_positive_ttl/cache_negative_ttl.
+	APIKeyCachePositiveTTL time.Duration
+	APIKeyCacheNegativeTTL time.Duration
+}
+
+// readAPIKeySettings populates cfg's [auth.api_key] fields from iniFile.
+func (cfg *Cfg) readAPIKeySettings(iniFile *ini.File) {
+	sec := iniFile.Section("auth.api_key")
+
+	cfg.TrustedProxies = splitAndTrim(sec.Key("trusted_proxies").MustString(""))
+	cfg.APIKeyAllowedTransports = splitAndTrim(sec.Key("allowed_transports").MustString(""))
+	cfg.APIKeyHeaderName = sec.Key("header_name").MustString("")
+	cfg.APIKeyCacheSize = sec.Key("cache_size").MustInt(5000)
+	cfg.APIKeyCachePositiveTTL = sec.Key("cache_positive_ttl").MustDuration(30 * time.Second)
+	cfg.APIKeyCacheNegativeTTL = sec.Key("cache_negative_ttl").MustDuration(5 * time.Second)
+}
+
+// splitAndTrim splits a comma-separated ini value into its trimmed,
+// non-empty parts, returning nil for an empty or all-blank input. A key
+// that is missing and a key set to an empty value both yield nil.
+func splitAndTrim(raw string) []string {
+	if strings.TrimSpace(raw) == "" {
+		return nil
+	}
+
+	parts := strings.Split(raw, ",")
+	out := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}